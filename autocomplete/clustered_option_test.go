@@ -0,0 +1,68 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package autocomplete
+
+import (
+	"testing"
+
+	"github.com/microsoft/clac/autocomplete/model"
+)
+
+func TestExpandClusteredOption(t *testing.T) {
+	options := []model.Option{
+		{Name: []string{"-x"}},
+		{Name: []string{"-v"}},
+		{Name: []string{"-f"}},
+	}
+
+	token := commandToken{token: "-xvf", complete: true, isOption: true}
+	expanded, ok := expandClusteredOption(token, options)
+	if !ok {
+		t.Fatalf("expandClusteredOption(%q) = _, false, want true", token.token)
+	}
+	want := []string{"-x", "-v", "-f"}
+	if len(expanded) != len(want) {
+		t.Fatalf("expandClusteredOption(%q) = %v, want %v", token.token, expanded, want)
+	}
+	for i, flag := range expanded {
+		if flag.token != want[i] {
+			t.Errorf("expanded[%d].token = %q, want %q", i, flag.token, want[i])
+		}
+		if !flag.complete {
+			t.Errorf("expanded[%d].complete = false, want true", i)
+		}
+	}
+}
+
+func TestExpandClusteredOption_UnknownFlagFallsBack(t *testing.T) {
+	options := []model.Option{
+		{Name: []string{"-x"}},
+		{Name: []string{"-v"}},
+	}
+	token := commandToken{token: "-xvf", complete: true, isOption: true}
+	if _, ok := expandClusteredOption(token, options); ok {
+		t.Fatalf("expandClusteredOption(%q) = _, true, want false (unknown flag 'f')", token.token)
+	}
+}
+
+func TestExpandClusteredOption_IgnoresLongOptionsAndSingleFlags(t *testing.T) {
+	options := []model.Option{{Name: []string{"-x"}}}
+
+	if _, ok := expandClusteredOption(commandToken{token: "--exclude", complete: true, isOption: true}, options); ok {
+		t.Errorf("expandClusteredOption(\"--exclude\") should not expand a long option")
+	}
+	if _, ok := expandClusteredOption(commandToken{token: "-x", complete: true, isOption: true}, options); ok {
+		t.Errorf("expandClusteredOption(\"-x\") should not expand a single flag")
+	}
+}
+
+func TestIsPersistentOption(t *testing.T) {
+	persistentOptions := []model.Option{{Name: []string{"-v", "--verbose"}}}
+	if !isPersistentOption("-v", persistentOptions) {
+		t.Errorf("isPersistentOption(\"-v\", ...) = false, want true")
+	}
+	if isPersistentOption("-q", persistentOptions) {
+		t.Errorf("isPersistentOption(\"-q\", ...) = true, want false")
+	}
+}
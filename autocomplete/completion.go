@@ -4,20 +4,23 @@
 package autocomplete
 
 import (
+	"container/list"
+	"fmt"
 	"log/slog"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/microsoft/clac/autocomplete/model"
 	"github.com/microsoft/clac/autocomplete/specs"
 )
 
-var (
-	cmdDelimiter       = regexp.MustCompile(`(\|\|)|(&&)|(;)`)
-	lastSuggestionCmd  = ""
-	lastSuggestion     = []Suggestion{}
-	lastArgDescription = ""
-	lastCmdRunes       = 0
-)
+var cmdDelimiter = regexp.MustCompile(`(\|\|)|(&&)|(;)|(\|)`)
+
+const fuzzyFilterStrategy = "fuzzy"
+
+const defaultSuggestionCacheSize = 128
 
 type Suggestion struct {
 	Name        string
@@ -25,6 +28,135 @@ type Suggestion struct {
 	Description string
 }
 
+type cachedResult struct {
+	suggestions    []Suggestion
+	argDescription string
+	charsInLastCmd int
+}
+
+type lruEntry struct {
+	key   string
+	value cachedResult
+}
+
+type suggestionLRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+func newSuggestionLRU(capacity int) *suggestionLRU {
+	if capacity <= 0 {
+		capacity = defaultSuggestionCacheSize
+	}
+	return &suggestionLRU{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *suggestionLRU) get(key string) (cachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return cachedResult{}, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+func (c *suggestionLRU) put(key string, value cachedResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	c.evictLocked()
+}
+
+func (c *suggestionLRU) evictLocked() {
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest == nil {
+			return
+		}
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *suggestionLRU) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *suggestionLRU) resize(capacity int) {
+	if capacity <= 0 {
+		capacity = defaultSuggestionCacheSize
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	c.evictLocked()
+}
+
+type prefixCacheEntry struct {
+	partial        string
+	suggestions    []Suggestion
+	argDescription string
+	filterStrategy string
+}
+
+type prefixCache struct {
+	mu      sync.Mutex
+	entries map[string]prefixCacheEntry
+}
+
+func newPrefixCache() *prefixCache {
+	return &prefixCache{entries: make(map[string]prefixCacheEntry)}
+}
+
+func (c *prefixCache) get(tokenPath string) (prefixCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[tokenPath]
+	return entry, ok
+}
+
+func (c *prefixCache) put(tokenPath string, entry prefixCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[tokenPath] = entry
+}
+
+func (c *prefixCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]prefixCacheEntry)
+}
+
+var (
+	suggestionCache       = newSuggestionLRU(defaultSuggestionCacheSize)
+	suggestionPrefixCache = newPrefixCache()
+)
+
+func ResetSuggestionCache() {
+	suggestionCache.reset()
+	suggestionPrefixCache.reset()
+}
+
+func SetSuggestionCacheSize(n int) {
+	suggestionCache.resize(n)
+}
+
 func getOption(token string, options []model.Option) *model.Option {
 	for _, option := range options {
 		for _, optionName := range option.Name {
@@ -88,6 +220,88 @@ func getPersistentTokens(tokens []model.ProcessedToken) []model.ProcessedToken {
 	return persistentTokens
 }
 
+func levenshtein(a, b string) int {
+	m, n := len(a), len(b)
+	prev := make([]int, n+1)
+	curr := make([]int, n+1)
+	for j := 0; j <= n; j++ {
+		prev[j] = j
+	}
+	for i := 1; i <= m; i++ {
+		curr[0] = i
+		for j := 1; j <= n; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[n]
+}
+
+func fuzzyMaxDistance(partial string) int {
+	if len(partial) <= 4 {
+		return 2
+	}
+	return 3
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func rankByFuzzy(suggestions []model.TermSuggestion, partial string) []model.TermSuggestion {
+	if partial == "" {
+		return suggestions
+	}
+	maxDistance := fuzzyMaxDistance(partial)
+
+	type scoredSuggestion struct {
+		suggestion model.TermSuggestion
+		distance   int
+		prefixLen  int
+	}
+	scored := make([]scoredSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if abs(len(suggestion.Name)-len(partial)) > maxDistance {
+			continue
+		}
+		distance := levenshtein(suggestion.Name, partial)
+		if distance > maxDistance {
+			continue
+		}
+		scored = append(scored, scoredSuggestion{suggestion, distance, commonPrefixLen(suggestion.Name, partial)})
+	}
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].distance != scored[j].distance {
+			return scored[i].distance < scored[j].distance
+		}
+		if scored[i].prefixLen != scored[j].prefixLen {
+			return scored[i].prefixLen > scored[j].prefixLen
+		}
+		return scored[i].suggestion.Name < scored[j].suggestion.Name
+	})
+
+	ranked := make([]model.TermSuggestion, len(scored))
+	for i, s := range scored {
+		ranked[i] = s.suggestion
+	}
+	return ranked
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
 func getPersistentOptions(existingOptions []model.Option, newOptions []model.Option) []model.Option {
 	exists := make(map[string]struct{})
 	for _, existingOption := range existingOptions {
@@ -110,7 +324,160 @@ func getPersistentOptions(existingOptions []model.Option, newOptions []model.Opt
 	return existingOptions
 }
 
-func getSubcommandDrivenRecommendation(spec model.Subcommand, persistentOptions []model.Option, partialCmd *commandToken, argsDepleted bool, argsFromSubcommand bool, acceptedTokens []model.ProcessedToken) model.TermSuggestions {
+func filterConflictingOptions(suggestions []model.TermSuggestion, options []model.Option, acceptedTokens []model.ProcessedToken) []model.TermSuggestion {
+	aliasToPrimary := make(map[string]string)
+	for _, option := range options {
+		primary := getLongName(option.Name)
+		for _, name := range option.Name {
+			aliasToPrimary[name] = primary
+		}
+	}
+
+	acceptedPrimaries := make(map[string]struct{}, len(acceptedTokens))
+	for _, token := range acceptedTokens {
+		if primary, ok := aliasToPrimary[token.Token]; ok {
+			acceptedPrimaries[primary] = struct{}{}
+		}
+	}
+
+	acceptedGroups := make(map[string]struct{})
+	for _, option := range options {
+		if option.Group == "" {
+			continue
+		}
+		if _, ok := acceptedPrimaries[getLongName(option.Name)]; ok {
+			acceptedGroups[option.Group] = struct{}{}
+		}
+	}
+
+	suppressed := make(map[string]struct{})
+	for _, option := range options {
+		blocked := false
+		for _, conflict := range option.ConflictsWith {
+			if _, ok := acceptedPrimaries[aliasToPrimary[conflict]]; ok {
+				blocked = true
+				break
+			}
+		}
+		if !blocked && option.Group != "" {
+			if _, ok := acceptedGroups[option.Group]; ok {
+				_, chosen := acceptedPrimaries[getLongName(option.Name)]
+				blocked = !chosen
+			}
+		}
+		if !blocked && len(option.RequiresAnyOf) > 0 {
+			satisfied := false
+			for _, prereq := range option.RequiresAnyOf {
+				if _, ok := acceptedPrimaries[aliasToPrimary[prereq]]; ok {
+					satisfied = true
+					break
+				}
+			}
+			blocked = !satisfied
+		}
+		if blocked {
+			for _, name := range option.Name {
+				suppressed[name] = struct{}{}
+			}
+		}
+	}
+	if len(suppressed) == 0 {
+		return suggestions
+	}
+
+	filtered := make([]model.TermSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if _, ok := suppressed[suggestion.Name]; ok {
+			continue
+		}
+		filtered = append(filtered, suggestion)
+	}
+	return filtered
+}
+
+// ValidateOptionGroups reports a typo in ConflictsWith/RequiresAnyOf
+// that doesn't name a real option, or a cycle in RequiresAnyOf.
+func ValidateOptionGroups(options []model.Option) error {
+	aliasToPrimary := make(map[string]string)
+	for _, option := range options {
+		primary := getLongName(option.Name)
+		for _, name := range option.Name {
+			aliasToPrimary[name] = primary
+		}
+	}
+
+	requiresEdges := make(map[string][]string)
+	for _, option := range options {
+		primary := getLongName(option.Name)
+		for _, conflict := range option.ConflictsWith {
+			if _, ok := aliasToPrimary[conflict]; !ok {
+				return fmt.Errorf("option %q: ConflictsWith references unknown option %q", primary, conflict)
+			}
+		}
+		if len(option.RequiresAnyOf) == 0 {
+			continue
+		}
+		resolved := make([]string, 0, len(option.RequiresAnyOf))
+		for _, prereq := range option.RequiresAnyOf {
+			primaryPrereq, ok := aliasToPrimary[prereq]
+			if !ok {
+				return fmt.Errorf("option %q: RequiresAnyOf references unknown option %q", primary, prereq)
+			}
+			resolved = append(resolved, primaryPrereq)
+		}
+		requiresEdges[primary] = resolved
+	}
+
+	visiting := make(map[string]bool)
+	visited := make(map[string]bool)
+	var detectCycle func(name string, path []string) error
+	detectCycle = func(name string, path []string) error {
+		if visiting[name] {
+			return fmt.Errorf("cycle detected in RequiresAnyOf: %s", strings.Join(append(path, name), " -> "))
+		}
+		if visited[name] {
+			return nil
+		}
+		visiting[name] = true
+		for _, dep := range requiresEdges[name] {
+			if err := detectCycle(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+		return nil
+	}
+	for primary := range requiresEdges {
+		if err := detectCycle(primary, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	for name, spec := range specs.Specs {
+		if err := validateSubcommandOptionGroups(name, spec); err != nil {
+			slog.Error("invalid option group declaration", "command", name, "error", err)
+		}
+	}
+}
+
+func validateSubcommandOptionGroups(path string, spec model.Subcommand) error {
+	if err := ValidateOptionGroups(spec.Options); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, subcommand := range spec.Subcommands {
+		subcommandPath := path + " " + getLongName(subcommand.Name)
+		if err := validateSubcommandOptionGroups(subcommandPath, subcommand); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getSubcommandDrivenRecommendation(spec model.Subcommand, persistentOptions []model.Option, partialCmd *commandToken, argsDepleted bool, argsFromSubcommand bool, acceptedTokens []model.ProcessedToken, pipelineContext model.PipelineContext) model.TermSuggestions {
 	suggestions := []model.TermSuggestion{}
 	allOptions := append(spec.Options, persistentOptions...)
 	var partialToken *string = nil
@@ -125,7 +492,7 @@ func getSubcommandDrivenRecommendation(spec model.Subcommand, persistentOptions
 	}
 	if len(spec.Args) != 0 {
 		activeArg := spec.Args[0]
-		getGeneratorDrivenRecommendations(activeArg.Generator, &suggestions, partialToken, spec.FilterStrategy, acceptedTokens)
+		getGeneratorDrivenRecommendations(activeArg.Generator, &suggestions, partialToken, spec.FilterStrategy, acceptedTokens, pipelineContext)
 		getSuggestionDrivenRecommendations(activeArg.Suggestions, &suggestions, partialToken, spec.FilterStrategy)
 		getTemplateDrivenRecommendations(activeArg.Templates, &suggestions, partialToken, spec.FilterStrategy)
 	}
@@ -133,14 +500,19 @@ func getSubcommandDrivenRecommendation(spec model.Subcommand, persistentOptions
 		getSubcommandDrivenRecommendations(spec, &suggestions, partialToken, spec.FilterStrategy)
 		getOptionDrivenRecommendations(allOptions, &suggestions, acceptedTokens, partialToken, spec.FilterStrategy)
 	}
+	if spec.FilterStrategy == fuzzyFilterStrategy && partialToken != nil {
+		suggestions = rankByFuzzy(suggestions, *partialToken)
+	}
+	suggestions = filterConflictingOptions(suggestions, allOptions, acceptedTokens)
 	removeDuplicateRecommendation(&suggestions, acceptedTokens)
 
 	return model.TermSuggestions{
-		Suggestions: suggestions,
+		Suggestions:    suggestions,
+		FilterStrategy: spec.FilterStrategy,
 	}
 }
 
-func getArgDrivenRecommendation(args []model.Arg, spec model.Subcommand, persistentOptions []model.Option, partialCmd *commandToken, acceptedTokens []model.ProcessedToken, variadicArgIsBound bool) model.TermSuggestions {
+func getArgDrivenRecommendation(args []model.Arg, spec model.Subcommand, persistentOptions []model.Option, partialCmd *commandToken, acceptedTokens []model.ProcessedToken, variadicArgIsBound bool, pipelineContext model.PipelineContext) model.TermSuggestions {
 	suggestions := []model.TermSuggestion{}
 	activeArg := args[0]
 	allOptions := append(spec.Options, persistentOptions...)
@@ -149,7 +521,7 @@ func getArgDrivenRecommendation(args []model.Arg, spec model.Subcommand, persist
 		partialToken = &partialCmd.token
 	}
 
-	getGeneratorDrivenRecommendations(activeArg.Generator, &suggestions, partialToken, activeArg.FilterStrategy, acceptedTokens)
+	getGeneratorDrivenRecommendations(activeArg.Generator, &suggestions, partialToken, activeArg.FilterStrategy, acceptedTokens, pipelineContext)
 	getSuggestionDrivenRecommendations(activeArg.Suggestions, &suggestions, partialToken, activeArg.FilterStrategy)
 	getTemplateDrivenRecommendations(activeArg.Templates, &suggestions, partialToken, activeArg.FilterStrategy)
 
@@ -157,6 +529,10 @@ func getArgDrivenRecommendation(args []model.Arg, spec model.Subcommand, persist
 		getSubcommandDrivenRecommendations(spec, &suggestions, partialToken, activeArg.FilterStrategy)
 		getOptionDrivenRecommendations(allOptions, &suggestions, acceptedTokens, partialToken, activeArg.FilterStrategy)
 	}
+	if activeArg.FilterStrategy == fuzzyFilterStrategy && partialToken != nil {
+		suggestions = rankByFuzzy(suggestions, *partialToken)
+	}
+	suggestions = filterConflictingOptions(suggestions, allOptions, acceptedTokens)
 	removeDuplicateRecommendation(&suggestions, acceptedTokens)
 
 	argDescriptionSuggestion := ""
@@ -167,100 +543,183 @@ func getArgDrivenRecommendation(args []model.Arg, spec model.Subcommand, persist
 	return model.TermSuggestions{
 		ArgumentDescription: argDescriptionSuggestion,
 		Suggestions:         suggestions,
+		FilterStrategy:      activeArg.FilterStrategy,
+	}
+}
+
+func isPersistentOption(token string, persistentOptions []model.Option) bool {
+	for _, persistentOption := range persistentOptions {
+		for _, persistentOptionName := range persistentOption.Name {
+			if token == persistentOptionName {
+				return true
+			}
+		}
 	}
+	return false
 }
 
-func handleSubcommand(tokens []commandToken, spec model.Subcommand, persistentOptions []model.Option, argsDepleted, argsUsed bool, acceptedTokens []model.ProcessedToken) (suggestions model.TermSuggestions) {
+func expandClusteredOption(token commandToken, options []model.Option) ([]commandToken, bool) {
+	if !token.isOption || len(token.token) <= 2 || token.token[1] == '-' {
+		return nil, false
+	}
+	flagChars := token.token[1:]
+	expanded := make([]commandToken, 0, len(flagChars))
+	for _, r := range flagChars {
+		flagToken := token
+		flagToken.token = "-" + string(r)
+		flagToken.complete = true
+		if getOption(flagToken.token, options) == nil {
+			return nil, false
+		}
+		expanded = append(expanded, flagToken)
+	}
+	return expanded, true
+}
+
+func handleClusteredOptions(flags []commandToken, rest []commandToken, spec model.Subcommand, persistentOptions []model.Option, acceptedTokens []model.ProcessedToken, pipelineContext model.PipelineContext) (suggestions model.TermSuggestions) {
+	allOptions := append(spec.Options, persistentOptions...)
+	for i, flag := range flags {
+		option := getOption(flag.token, allOptions)
+		if option == nil {
+			slog.Error("invalid state reached, clustered option resolved to unknown flag", "flag", flag.token)
+			return
+		}
+		if i == len(flags)-1 {
+			return handleOption(append([]commandToken{flag}, rest...), *option, spec, persistentOptions, acceptedTokens, pipelineContext)
+		}
+		acceptedTokens = append(acceptedTokens, model.ProcessedToken{Token: flag.token, Persist: isPersistentOption(flag.token, persistentOptions)})
+	}
+	return
+}
+
+func handleSubcommand(tokens []commandToken, spec model.Subcommand, persistentOptions []model.Option, argsDepleted, argsUsed bool, acceptedTokens []model.ProcessedToken, pipelineContext model.PipelineContext) (suggestions model.TermSuggestions) {
 	if len(tokens) == 0 {
-		return getSubcommandDrivenRecommendation(spec, persistentOptions, nil, argsDepleted, argsUsed, acceptedTokens)
+		return getSubcommandDrivenRecommendation(spec, persistentOptions, nil, argsDepleted, argsUsed, acceptedTokens, pipelineContext)
 	} else if !tokens[0].complete {
-		return getSubcommandDrivenRecommendation(spec, persistentOptions, &tokens[0], argsDepleted, argsUsed, acceptedTokens)
+		return getSubcommandDrivenRecommendation(spec, persistentOptions, &tokens[0], argsDepleted, argsUsed, acceptedTokens, pipelineContext)
 	}
 
 	persistentOptions = getPersistentOptions(persistentOptions, spec.Options)
 	activeCmd := tokens[0]
 	if activeCmd.isOption {
-		if option := getOption(activeCmd.token, append(spec.Options, persistentOptions...)); option != nil {
-			return handleOption(tokens, *option, spec, persistentOptions, acceptedTokens)
+		allOptions := append(spec.Options, persistentOptions...)
+		if option := getOption(activeCmd.token, allOptions); option != nil {
+			return handleOption(tokens, *option, spec, persistentOptions, acceptedTokens, pipelineContext)
+		}
+		if flags, ok := expandClusteredOption(activeCmd, allOptions); ok {
+			return handleClusteredOptions(flags, tokens[1:], spec, persistentOptions, acceptedTokens, pipelineContext)
 		}
 		return
 	}
 	if subcommand := getSubcommand(activeCmd.token, spec); subcommand != nil {
-		return handleSubcommand(tokens[1:], *subcommand, persistentOptions, false, false, getPersistentTokens(acceptedTokens))
+		return handleSubcommand(tokens[1:], *subcommand, persistentOptions, false, false, getPersistentTokens(acceptedTokens), pipelineContext)
 	}
 
 	if len(spec.Args) == 0 { // not subcommand or option & no args exist
 		return
 	}
 
-	return handleArg(tokens, spec.Args, spec, persistentOptions, acceptedTokens, false, false)
+	return handleArg(tokens, spec.Args, spec, persistentOptions, acceptedTokens, false, false, pipelineContext)
 }
 
-func handleOption(tokens []commandToken, option model.Option, spec model.Subcommand, persistentOptions []model.Option, acceptedTokens []model.ProcessedToken) (suggestions model.TermSuggestions) {
+func handleOption(tokens []commandToken, option model.Option, spec model.Subcommand, persistentOptions []model.Option, acceptedTokens []model.ProcessedToken, pipelineContext model.PipelineContext) (suggestions model.TermSuggestions) {
 	if len(tokens) == 0 {
 		slog.Error("invalid state reached, option with no tokens")
 		return
 	}
 	activeOption := tokens[0]
-	isPersistent := false
-	for _, persistentOption := range persistentOptions {
-		for _, persistentOptionName := range persistentOption.Name {
-			if activeOption.token == persistentOptionName {
-				isPersistent = true
-				goto persistenceDetermined
-			}
-		}
-	}
-persistenceDetermined:
-	acceptedTokens = append(acceptedTokens, model.ProcessedToken{Token: activeOption.token, Persist: isPersistent})
+	acceptedTokens = append(acceptedTokens, model.ProcessedToken{Token: activeOption.token, Persist: isPersistentOption(activeOption.token, persistentOptions)})
 	if len(option.Args) == 0 {
-		return handleSubcommand(tokens[1:], spec, persistentOptions, false, false, acceptedTokens)
+		return handleSubcommand(tokens[1:], spec, persistentOptions, false, false, acceptedTokens, pipelineContext)
 	}
-	return handleArg(tokens[1:], option.Args, spec, persistentOptions, acceptedTokens, true, false)
+	return handleArg(tokens[1:], option.Args, spec, persistentOptions, acceptedTokens, true, false, pipelineContext)
 }
 
-func handleArg(tokens []commandToken, args []model.Arg, spec model.Subcommand, persistentOptions []model.Option, acceptedTokens []model.ProcessedToken, fromOption, fromVariadic bool) (suggestions model.TermSuggestions) {
+func handleArg(tokens []commandToken, args []model.Arg, spec model.Subcommand, persistentOptions []model.Option, acceptedTokens []model.ProcessedToken, fromOption, fromVariadic bool, pipelineContext model.PipelineContext) (suggestions model.TermSuggestions) {
 	if len(args) == 0 {
-		return handleSubcommand(tokens, spec, persistentOptions, true, !fromOption, acceptedTokens)
+		return handleSubcommand(tokens, spec, persistentOptions, true, !fromOption, acceptedTokens, pipelineContext)
 	} else if len(tokens) == 0 {
-		return getArgDrivenRecommendation(args, spec, persistentOptions, nil, acceptedTokens, fromVariadic)
+		return getArgDrivenRecommendation(args, spec, persistentOptions, nil, acceptedTokens, fromVariadic, pipelineContext)
 	} else if !tokens[0].complete {
-		return getArgDrivenRecommendation(args, spec, persistentOptions, &tokens[0], acceptedTokens, fromVariadic)
+		return getArgDrivenRecommendation(args, spec, persistentOptions, &tokens[0], acceptedTokens, fromVariadic, pipelineContext)
 	}
 
 	activeCmd := tokens[0]
 	if argsAreOptional(args) {
 		if activeCmd.isOption {
-			if option := getOption(activeCmd.token, append(spec.Options, persistentOptions...)); option != nil {
-				return handleOption(tokens, *option, spec, persistentOptions, acceptedTokens)
+			allOptions := append(spec.Options, persistentOptions...)
+			if option := getOption(activeCmd.token, allOptions); option != nil {
+				return handleOption(tokens, *option, spec, persistentOptions, acceptedTokens, pipelineContext)
+			}
+			if flags, ok := expandClusteredOption(activeCmd, allOptions); ok {
+				return handleClusteredOptions(flags, tokens[1:], spec, persistentOptions, acceptedTokens, pipelineContext)
 			}
 			return
 		}
 		subcommand := getSubcommand(activeCmd.token, spec)
 		if subcommand != nil {
-			return handleSubcommand(tokens[1:], *subcommand, persistentOptions, false, false, getPersistentTokens(acceptedTokens))
+			return handleSubcommand(tokens[1:], *subcommand, persistentOptions, false, false, getPersistentTokens(acceptedTokens), pipelineContext)
 		}
 	}
 
 	activeArg := args[0]
 	acceptedTokens = append(acceptedTokens, model.ProcessedToken{Token: activeCmd.token, Persist: false})
 	if activeArg.IsVariadic {
-		return handleArg(tokens[1:], args, spec, persistentOptions, acceptedTokens, fromOption, true)
+		return handleArg(tokens[1:], args, spec, persistentOptions, acceptedTokens, fromOption, true, pipelineContext)
 	} else if activeArg.IsCommand {
 		if len(tokens) <= 1 {
 			return
 		}
 		activeCmd = tokens[1]
 		if subcommand := getSubcommand(activeCmd.token, spec); subcommand != nil {
-			return handleSubcommand(tokens[2:], *subcommand, persistentOptions, false, false, []model.ProcessedToken{})
+			return handleSubcommand(tokens[2:], *subcommand, persistentOptions, false, false, []model.ProcessedToken{}, pipelineContext)
 		}
 		return
 	}
-	return handleArg(tokens[1:], args[1:], spec, persistentOptions, acceptedTokens, fromOption, false)
+	return handleArg(tokens[1:], args[1:], spec, persistentOptions, acceptedTokens, fromOption, false, pipelineContext)
+}
+
+func splitPipelineSegments(cmd string) []string {
+	locs := cmdDelimiter.FindAllStringIndex(cmd, -1)
+	if len(locs) == 0 {
+		return []string{cmd}
+	}
+	segments := make([]string, 0, len(locs)+1)
+	start := 0
+	for _, loc := range locs {
+		segments = append(segments, cmd[start:loc[0]])
+		start = loc[1]
+	}
+	segments = append(segments, cmd[start:])
+	return segments
+}
+
+// pipelineUpstreamCommand joins the complete tokens of an upstream
+// pipeline segment (e.g. "git", "log") into "git log", so a generator
+// can condition on the full invocation rather than just the root
+// command ("git log" vs "git status" both piping into the same command).
+func pipelineUpstreamCommand(tokens []commandToken) string {
+	parts := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !token.complete {
+			break
+		}
+		parts = append(parts, token.token)
+	}
+	return strings.Join(parts, " ")
 }
 
 func loadSuggestions(cmd string) (suggestions model.TermSuggestions, charsInLastCmd int) {
-	activeCmd := ParseCommand(cmd)
+	segments := splitPipelineSegments(cmd)
+	pipedFrom := make([]string, 0, len(segments)-1)
+	for _, segment := range segments[:len(segments)-1] {
+		if upstreamTokens := ParseCommand(segment); len(upstreamTokens) > 0 && upstreamTokens[0].complete {
+			pipedFrom = append(pipedFrom, pipelineUpstreamCommand(upstreamTokens))
+		}
+	}
+	pipelineContext := model.PipelineContext{PipedFrom: pipedFrom}
+
+	activeCmd := ParseCommand(segments[len(segments)-1])
 	if len(activeCmd) <= 0 {
 		return
 	}
@@ -274,22 +733,73 @@ func loadSuggestions(cmd string) (suggestions model.TermSuggestions, charsInLast
 		charsInLastCmd = 0
 	}
 	if spec, ok := specs.Specs[rootToken.token]; ok {
-		return handleSubcommand(activeCmd[1:], spec, []model.Option{}, false, false, []model.ProcessedToken{}), charsInLastCmd
+		return handleSubcommand(activeCmd[1:], spec, []model.Option{}, false, false, []model.ProcessedToken{}, pipelineContext), charsInLastCmd
 	}
 	return
 }
 
-func LoadSuggestions(cmd string) ([]Suggestion, string, int) {
-	if cmd == lastSuggestionCmd {
-		return lastSuggestion, lastArgDescription, lastCmdRunes
+func splitTrailingToken(cmd string) (tokenPath, partial string) {
+	idx := strings.LastIndexAny(cmd, " \t")
+	if idx == -1 {
+		return "", cmd
 	}
-	termSuggestions, lastRunes := loadSuggestions(cmd)
+	return cmd[:idx+1], cmd[idx+1:]
+}
+
+func filterSuggestionsByPrefix(suggestions []Suggestion, partial string) []Suggestion {
+	filtered := make([]Suggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if strings.HasPrefix(suggestion.Name, partial) {
+			filtered = append(filtered, suggestion)
+		}
+	}
+	return filtered
+}
 
+// canReuseCachedPrefix reports whether entry's suggestion list can be
+// re-filtered for partial instead of recomputing it from scratch. That's
+// only valid when entry was itself filtered by literal prefix, since
+// that kind of filtering can only narrow the list as partial grows. A
+// "fuzzy" entry can't be reused this way: a candidate too far from a
+// short partial (e.g. "origin" vs "o") can become a valid match once
+// partial grows (e.g. "orig"), so it must be recomputed on every
+// keystroke instead.
+func canReuseCachedPrefix(entry prefixCacheEntry, partial string) bool {
+	return entry.filterStrategy != fuzzyFilterStrategy && strings.HasPrefix(partial, entry.partial)
+}
+
+func computeSuggestions(cmd string) cachedResult {
+	tokenPath, partial := splitTrailingToken(cmd)
+	if entry, ok := suggestionPrefixCache.get(tokenPath); ok && canReuseCachedPrefix(entry, partial) {
+		return cachedResult{
+			suggestions:    filterSuggestionsByPrefix(entry.suggestions, partial),
+			argDescription: entry.argDescription,
+			charsInLastCmd: len(partial),
+		}
+	}
+
+	termSuggestions, lastRunes := loadSuggestions(cmd)
 	suggestions := []Suggestion{}
 	for _, suggestion := range termSuggestions.Suggestions {
 		icon := model.TermIcons[suggestion.Type]
 		suggestions = append(suggestions, Suggestion{Name: suggestion.Name, NamePrefix: icon, Description: suggestion.Description})
 	}
-	lastSuggestionCmd, lastSuggestion, lastCmdRunes, lastArgDescription = cmd, suggestions, lastRunes, termSuggestions.ArgumentDescription
-	return suggestions, termSuggestions.ArgumentDescription, lastRunes
+
+	suggestionPrefixCache.put(tokenPath, prefixCacheEntry{
+		partial:        partial,
+		suggestions:    suggestions,
+		argDescription: termSuggestions.ArgumentDescription,
+		filterStrategy: termSuggestions.FilterStrategy,
+	})
+	return cachedResult{suggestions: suggestions, argDescription: termSuggestions.ArgumentDescription, charsInLastCmd: lastRunes}
+}
+
+func LoadSuggestions(cmd string) ([]Suggestion, string, int) {
+	if cached, ok := suggestionCache.get(cmd); ok {
+		return cached.suggestions, cached.argDescription, cached.charsInLastCmd
+	}
+
+	result := computeSuggestions(cmd)
+	suggestionCache.put(cmd, result)
+	return result.suggestions, result.argDescription, result.charsInLastCmd
 }
@@ -0,0 +1,48 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package autocomplete
+
+import "testing"
+
+func TestCanReuseCachedPrefix(t *testing.T) {
+	cases := []struct {
+		name    string
+		entry   prefixCacheEntry
+		partial string
+		want    bool
+	}{
+		{"prefix strategy, extended partial", prefixCacheEntry{partial: "m", filterStrategy: ""}, "ma", true},
+		{"prefix strategy, shorter partial", prefixCacheEntry{partial: "ma", filterStrategy: ""}, "m", false},
+		{"fuzzy strategy never reused", prefixCacheEntry{partial: "o", filterStrategy: fuzzyFilterStrategy}, "or", false},
+	}
+	for _, c := range cases {
+		if got := canReuseCachedPrefix(c.entry, c.partial); got != c.want {
+			t.Errorf("%s: canReuseCachedPrefix(%+v, %q) = %v, want %v", c.name, c.entry, c.partial, got, c.want)
+		}
+	}
+}
+
+func BenchmarkLoadSuggestions_RepeatedCommand(b *testing.B) {
+	ResetSuggestionCache()
+	cmd := "git checkout "
+	LoadSuggestions(cmd) // warm the LRU cache
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		LoadSuggestions(cmd)
+	}
+}
+
+func BenchmarkLoadSuggestions_IncrementalTyping(b *testing.B) {
+	ResetSuggestionCache()
+	prefixes := []string{"git checkout m", "git checkout ma", "git checkout mai", "git checkout main"}
+	LoadSuggestions("git checkout ") // populate the prefix cache for the token path
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, cmd := range prefixes {
+			LoadSuggestions(cmd)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package autocomplete
+
+import (
+	"testing"
+
+	"github.com/microsoft/clac/autocomplete/model"
+)
+
+func TestLevenshtein(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"abc", "abc", 0},
+		{"origin", "orig", 2},
+		{"origin", "o", 5},
+		{"kitten", "sitting", 3},
+	}
+	for _, c := range cases {
+		if got := levenshtein(c.a, c.b); got != c.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestFuzzyMaxDistance(t *testing.T) {
+	cases := []struct {
+		partial string
+		want    int
+	}{
+		{"", 2},
+		{"abcd", 2},
+		{"abcde", 3},
+	}
+	for _, c := range cases {
+		if got := fuzzyMaxDistance(c.partial); got != c.want {
+			t.Errorf("fuzzyMaxDistance(%q) = %d, want %d", c.partial, got, c.want)
+		}
+	}
+}
+
+func TestCommonPrefixLen(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"origin", "orig", 4},
+		{"origin", "origin", 6},
+		{"origin", "foo", 0},
+		{"", "origin", 0},
+	}
+	for _, c := range cases {
+		if got := commonPrefixLen(c.a, c.b); got != c.want {
+			t.Errorf("commonPrefixLen(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestRankByFuzzy(t *testing.T) {
+	suggestions := []model.TermSuggestion{
+		{Name: "origin"},
+		{Name: "original"},
+		{Name: "other"},
+		{Name: "orig"},
+	}
+
+	ranked := rankByFuzzy(suggestions, "orig")
+	names := make([]string, len(ranked))
+	for i, s := range ranked {
+		names[i] = s.Name
+	}
+
+	// "original" is excluded by the length short-circuit: abs(len("original")-len("orig")) = 4 > fuzzyMaxDistance("orig") = 2.
+	want := []string{"orig", "origin"}
+	if len(names) != len(want) {
+		t.Fatalf("rankByFuzzy(...) = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("rankByFuzzy(...)[%d] = %q, want %q", i, names[i], want[i])
+		}
+	}
+}
+
+func TestRankByFuzzy_EmptyPartialReturnsUnfiltered(t *testing.T) {
+	suggestions := []model.TermSuggestion{{Name: "origin"}, {Name: "other"}}
+	ranked := rankByFuzzy(suggestions, "")
+	if len(ranked) != len(suggestions) {
+		t.Fatalf("rankByFuzzy(..., \"\") = %v, want all suggestions unfiltered", ranked)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package autocomplete
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/microsoft/clac/autocomplete/model"
+)
+
+func TestFilterConflictingOptions(t *testing.T) {
+	options := []model.Option{
+		{Name: []string{"--json"}, ConflictsWith: []string{"--yaml"}},
+		{Name: []string{"--yaml"}, ConflictsWith: []string{"--json"}},
+		{Name: []string{"--short"}, Group: "format"},
+		{Name: []string{"--long"}, Group: "format"},
+		{Name: []string{"--force"}, RequiresAnyOf: []string{"--yes"}},
+		{Name: []string{"--yes"}},
+	}
+	suggestions := []model.TermSuggestion{
+		{Name: "--json"}, {Name: "--yaml"}, {Name: "--short"}, {Name: "--long"}, {Name: "--force"}, {Name: "--yes"},
+	}
+
+	cases := []struct {
+		name     string
+		accepted []model.ProcessedToken
+		want     []string
+	}{
+		{
+			name:     "conflict suppresses the other option",
+			accepted: []model.ProcessedToken{{Token: "--json"}},
+			want:     []string{"--json", "--short", "--long", "--yes"},
+		},
+		{
+			name:     "picking one option in a group suppresses the rest of the group",
+			accepted: []model.ProcessedToken{{Token: "--short"}},
+			want:     []string{"--json", "--yaml", "--short", "--yes"},
+		},
+		{
+			name:     "unmet prerequisite hides the dependent option",
+			accepted: []model.ProcessedToken{},
+			want:     []string{"--json", "--yaml", "--short", "--long", "--yes"},
+		},
+		{
+			name:     "met prerequisite keeps the dependent option",
+			accepted: []model.ProcessedToken{{Token: "--yes"}},
+			want:     []string{"--json", "--yaml", "--short", "--long", "--force", "--yes"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := filterConflictingOptions(suggestions, options, c.accepted)
+			names := make([]string, len(got))
+			for i, s := range got {
+				names[i] = s.Name
+			}
+			if len(names) != len(c.want) {
+				t.Fatalf("filterConflictingOptions(...) = %v, want %v", names, c.want)
+			}
+			for i := range c.want {
+				if names[i] != c.want[i] {
+					t.Errorf("filterConflictingOptions(...) = %v, want %v", names, c.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestFilterConflictingOptions_ResolvesAliases(t *testing.T) {
+	options := []model.Option{
+		{Name: []string{"-j", "--json"}, ConflictsWith: []string{"--yaml"}},
+		{Name: []string{"-y", "--yaml"}, ConflictsWith: []string{"--json"}},
+	}
+	suggestions := []model.TermSuggestion{{Name: "--json"}, {Name: "--yaml"}}
+
+	got := filterConflictingOptions(suggestions, options, []model.ProcessedToken{{Token: "-y"}})
+	if len(got) != 1 || got[0].Name != "--yaml" {
+		t.Fatalf("filterConflictingOptions(...) = %v, want only --yaml (accepting alias -y should suppress --json)", got)
+	}
+}
+
+func TestValidateOptionGroups(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []model.Option
+		wantErr string
+	}{
+		{
+			name: "valid groups and prerequisites",
+			options: []model.Option{
+				{Name: []string{"--json"}, ConflictsWith: []string{"--yaml"}},
+				{Name: []string{"--yaml"}, ConflictsWith: []string{"--json"}},
+				{Name: []string{"--force"}, RequiresAnyOf: []string{"--yes"}},
+				{Name: []string{"--yes"}},
+			},
+		},
+		{
+			name: "typo in ConflictsWith",
+			options: []model.Option{
+				{Name: []string{"--json"}, ConflictsWith: []string{"--yml"}},
+			},
+			wantErr: "ConflictsWith references unknown option",
+		},
+		{
+			name: "typo in RequiresAnyOf",
+			options: []model.Option{
+				{Name: []string{"--force"}, RequiresAnyOf: []string{"--yess"}},
+			},
+			wantErr: "RequiresAnyOf references unknown option",
+		},
+		{
+			name: "cycle in RequiresAnyOf",
+			options: []model.Option{
+				{Name: []string{"--a"}, RequiresAnyOf: []string{"--b"}},
+				{Name: []string{"--b"}, RequiresAnyOf: []string{"--a"}},
+			},
+			wantErr: "cycle detected in RequiresAnyOf",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateOptionGroups(c.options)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateOptionGroups(...) = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Fatalf("ValidateOptionGroups(...) = %v, want error containing %q", err, c.wantErr)
+			}
+		})
+	}
+}
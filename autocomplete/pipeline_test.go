@@ -0,0 +1,61 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package autocomplete
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPipelineSegments(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		want []string
+	}{
+		{"git status", []string{"git status"}},
+		{"git log | grep fix", []string{"git log ", " grep fix"}},
+		{"git status || echo fail", []string{"git status ", " echo fail"}},
+		{"make build && make test", []string{"make build ", " make test"}},
+		{"cd foo; ls", []string{"cd foo", " ls"}},
+		{"find . | xargs rm | echo done", []string{"find . ", " xargs rm ", " echo done"}},
+	}
+	for _, c := range cases {
+		got := splitPipelineSegments(c.cmd)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitPipelineSegments(%q) = %v, want %v", c.cmd, got, c.want)
+		}
+	}
+}
+
+func TestSplitPipelineSegments_DoublePipeIsNotTwoSingles(t *testing.T) {
+	got := splitPipelineSegments("git status || echo fail")
+	if len(got) != 2 {
+		t.Fatalf("splitPipelineSegments(\"git status || echo fail\") = %v, want 2 segments", got)
+	}
+}
+
+func TestPipelineUpstreamCommand(t *testing.T) {
+	cases := []struct {
+		tokens []commandToken
+		want   string
+	}{
+		{
+			tokens: []commandToken{{token: "git", complete: true}, {token: "log", complete: true}},
+			want:   "git log",
+		},
+		{
+			tokens: []commandToken{{token: "git", complete: true}, {token: "status", complete: true}},
+			want:   "git status",
+		},
+		{
+			tokens: []commandToken{{token: "git", complete: true}, {token: "lo", complete: false}},
+			want:   "git",
+		},
+	}
+	for _, c := range cases {
+		if got := pipelineUpstreamCommand(c.tokens); got != c.want {
+			t.Errorf("pipelineUpstreamCommand(%v) = %q, want %q", c.tokens, got, c.want)
+		}
+	}
+}